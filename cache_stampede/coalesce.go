@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// call represents an in-flight or just-completed SendRequest for a given
+// key, shared by every caller that asked for that key concurrently.
+type call struct {
+	wg       sync.WaitGroup
+	response string
+	err      error
+}
+
+// SendRequestCoalesced behaves like SendRequest but coalesces concurrent
+// callers requesting the same key into a single network round-trip: the
+// first caller becomes the leader and performs the request, while every
+// other caller waits for and shares the leader's result. Cancelling ctx
+// only stops a waiting (non-leader) caller from waiting; it never cancels
+// the leader's in-flight request, and the leader itself does not watch
+// its ctx.
+func (c *Client) SendRequestCoalesced(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	if existing, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		return waitForCall(ctx, existing)
+	}
+
+	leader := &call{}
+	leader.wg.Add(1)
+	c.inflight[key] = leader
+	c.mu.Unlock()
+
+	leader.response, leader.err = c.SendRequest(context.Background(), key)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+
+	leader.wg.Done()
+	return leader.response, leader.err
+}
+
+// waitForCall waits for an in-flight call to finish, returning early if
+// ctx is cancelled. It never affects the call itself.
+func waitForCall(ctx context.Context, cl *call) (string, error) {
+	done := make(chan struct{})
+	go func() {
+		cl.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return cl.response, cl.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
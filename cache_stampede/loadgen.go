@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LoadGenConfig configures a LoadGen run.
+type LoadGenConfig struct {
+	ServerAddr string
+	Protocol   ProtocolKind
+
+	Clients  int           // number of persistent worker connections
+	Duration time.Duration // how long to drive load for
+	RPS      int           // target requests/sec in open-loop mode; 0 means closed-loop (workers run flat out)
+	Keys     int           // size of the key space to pick from
+	ZipfS    float64       // Zipf skew parameter (>1); <= 1 means uniform key selection
+	Warmup   time.Duration // warmup period excluded from the report
+}
+
+// sample records one request's outcome for later aggregation.
+type sample struct {
+	timestamp time.Time
+	latency   time.Duration
+	err       bool
+}
+
+// LoadGenReport summarizes a completed LoadGen run.
+type LoadGenReport struct {
+	TotalRequests int           `json:"total_requests"`
+	Errors        int           `json:"errors"`
+	Duration      time.Duration `json:"duration"`
+	AchievedRPS   float64       `json:"achieved_rps"`
+	P50           time.Duration `json:"p50"`
+	P90           time.Duration `json:"p90"`
+	P99           time.Duration `json:"p99"`
+	P999          time.Duration `json:"p999"`
+
+	// ThroughputPerSecond[i] is the number of requests that started during
+	// second i, counted from the earliest request start in the report
+	// (excluding warmup).
+	ThroughputPerSecond []int `json:"throughput_per_second"`
+
+	samples []sample
+}
+
+// RunLoadGen drives load against cfg.ServerAddr for cfg.Duration (plus
+// cfg.Warmup, which is excluded from the report) and returns a report of
+// throughput and latency percentiles. Connections are leased from a Pool
+// sized to cfg.Clients, so a connection is never handed to two callers at
+// once even if a request runs long.
+func RunLoadGen(ctx context.Context, cfg LoadGenConfig) (*LoadGenReport, error) {
+	pool := NewPool(cfg.ServerAddr, cfg.Protocol, cfg.Clients, cfg.Clients, 0)
+	defer pool.Close()
+
+	keyPicker := newKeyPicker(cfg.Keys, cfg.ZipfS)
+
+	if cfg.Warmup > 0 {
+		fmt.Printf("🔥 Warming up for %v...\n", cfg.Warmup)
+		runLoadGenWindow(ctx, pool, cfg.Clients, keyPicker, cfg.RPS, cfg.Warmup, nil)
+	}
+
+	var samples []sample
+	var mu sync.Mutex
+	collect := func(s sample) {
+		mu.Lock()
+		samples = append(samples, s)
+		mu.Unlock()
+	}
+
+	start := time.Now()
+	runLoadGenWindow(ctx, pool, cfg.Clients, keyPicker, cfg.RPS, cfg.Duration, collect)
+	elapsed := time.Since(start)
+
+	return buildReport(samples, elapsed), nil
+}
+
+// runLoadGenWindow drives requests for duration, fanning out across at
+// most concurrency in-flight requests: either a fixed closed-loop
+// concurrency (rps <= 0, every worker loops back-to-back) or a target
+// open-loop RPS via a token-bucket ticker. Every request leases its
+// connection from pool for just its own duration, so a busy connection is
+// never handed out twice. collect, if non-nil, receives one sample per
+// completed request.
+func runLoadGenWindow(ctx context.Context, pool *Pool, concurrency int, pick func() string, rps int, duration time.Duration, collect func(sample)) {
+	deadline := time.Now().Add(duration)
+	var wg sync.WaitGroup
+
+	issue := func() {
+		client, err := pool.Get(ctx)
+		if err != nil {
+			if collect != nil {
+				collect(sample{timestamp: time.Now(), err: true})
+			}
+			return
+		}
+		key := pick()
+		start := time.Now()
+		_, err = client.SendRequest(ctx, key)
+		pool.Put(client)
+		if collect != nil {
+			collect(sample{timestamp: start, latency: time.Since(start), err: err != nil})
+		}
+	}
+
+	if rps <= 0 {
+		// Closed-loop: concurrency workers issue requests back-to-back, each
+		// leasing a pooled connection for the duration of every request.
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for time.Now().Before(deadline) {
+					issue()
+				}
+			}()
+		}
+		wg.Wait()
+		return
+	}
+
+	// Open-loop: a token-bucket ticker fires at the target RPS; each tick
+	// leases its own pooled connection rather than round-robining over a
+	// fixed slice of clients.
+	interval := time.Second / time.Duration(rps)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		<-ticker.C
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			issue()
+		}()
+	}
+	wg.Wait()
+}
+
+// newKeyPicker returns a function that draws a key name from a space of n
+// keys. s > 1 selects a Zipfian (hot-key) distribution with skew s;
+// otherwise keys are picked uniformly.
+func newKeyPicker(n int, s float64) func() string {
+	if n <= 1 {
+		return func() string { return "key-0" }
+	}
+	if s > 1 {
+		r := rand.New(rand.NewSource(1))
+		zipf := rand.NewZipf(r, s, 1, uint64(n-1))
+		return func() string { return "key-" + strconv.FormatUint(zipf.Uint64(), 10) }
+	}
+	r := rand.New(rand.NewSource(1))
+	return func() string { return "key-" + strconv.Itoa(r.Intn(n)) }
+}
+
+// buildReport aggregates raw samples into a LoadGenReport.
+func buildReport(samples []sample, elapsed time.Duration) *LoadGenReport {
+	report := &LoadGenReport{
+		TotalRequests: len(samples),
+		Duration:      elapsed,
+		samples:       samples,
+	}
+	if elapsed > 0 {
+		report.AchievedRPS = float64(len(samples)) / elapsed.Seconds()
+	}
+
+	latencies := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		if s.err {
+			report.Errors++
+			continue
+		}
+		latencies = append(latencies, s.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	report.P50 = percentile(latencies, 0.50)
+	report.P90 = percentile(latencies, 0.90)
+	report.P99 = percentile(latencies, 0.99)
+	report.P999 = percentile(latencies, 0.999)
+
+	if len(samples) > 0 {
+		// samples are appended in completion order, not start-time order (a
+		// slow request can finish, and get collected, after faster requests
+		// that started later) — find the true earliest start explicitly
+		// rather than assuming samples[0] is it.
+		earliest := samples[0].timestamp
+		for _, s := range samples {
+			if s.timestamp.Before(earliest) {
+				earliest = s.timestamp
+			}
+		}
+		for _, s := range samples {
+			bucket := int(s.timestamp.Sub(earliest) / time.Second)
+			if bucket < 0 {
+				bucket = 0
+			}
+			for len(report.ThroughputPerSecond) <= bucket {
+				report.ThroughputPerSecond = append(report.ThroughputPerSecond, 0)
+			}
+			report.ThroughputPerSecond[bucket]++
+		}
+	}
+
+	return report
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of a sorted
+// duration slice using the nearest-rank method, or 0 if the slice is
+// empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// Print writes a human-readable summary of the report to stdout.
+func (r *LoadGenReport) Print() {
+	fmt.Println("=" + fmt.Sprintf("%60s", "="))
+	fmt.Printf("📈 LoadGen Report\n")
+	fmt.Printf("   Total requests: %d (errors: %d)\n", r.TotalRequests, r.Errors)
+	fmt.Printf("   Duration:       %v\n", r.Duration)
+	fmt.Printf("   Achieved RPS:   %.1f\n", r.AchievedRPS)
+	fmt.Printf("   Latency p50:    %v\n", r.P50)
+	fmt.Printf("   Latency p90:    %v\n", r.P90)
+	fmt.Printf("   Latency p99:    %v\n", r.P99)
+	fmt.Printf("   Latency p999:   %v\n", r.P999)
+	fmt.Println("=" + fmt.Sprintf("%60s", "="))
+}
+
+// WriteJSON writes the report, excluding raw samples, as JSON to w.
+func (r *LoadGenReport) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteCSV writes one row per raw sample (timestamp, latency_ms, error) to w.
+func (r *LoadGenReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	if err := cw.Write([]string{"timestamp", "latency_ms", "error"}); err != nil {
+		return err
+	}
+	for _, s := range r.samples {
+		row := []string{
+			s.timestamp.Format(time.RFC3339Nano),
+			strconv.FormatFloat(float64(s.latency.Microseconds())/1000, 'f', 3, 64),
+			strconv.FormatBool(s.err),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return cw.Error()
+}
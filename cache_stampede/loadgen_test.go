@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(vals ...int) []time.Duration {
+		out := make([]time.Duration, len(vals))
+		for i, v := range vals {
+			out[i] = time.Duration(v) * time.Millisecond
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{"empty", ms(), 0.50, 0},
+		{"single value", ms(10), 0.99, 10 * time.Millisecond},
+		{"p50 of ten", ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0.50, 5 * time.Millisecond},
+		{"p90 of ten", ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0.90, 9 * time.Millisecond},
+		{"p99 clamps to max", ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0.99, 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := percentile(tt.sorted, tt.p)
+			if got != tt.want {
+				t.Errorf("percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildReportOutOfOrderCompletion reproduces samples arriving out of
+// start-time order: a request that started first can complete (and get
+// collected) after several requests that started later. Throughput
+// bucketing must not assume samples[0] is the earliest start.
+func TestBuildReportOutOfOrderCompletion(t *testing.T) {
+	base := time.Unix(1000, 0)
+
+	// Completion order: two fast requests that started at base+2s complete
+	// first, followed by a slow request that started at base (much
+	// earlier) but took long enough to finish last.
+	samples := []sample{
+		{timestamp: base.Add(2 * time.Second), latency: time.Millisecond},
+		{timestamp: base.Add(2 * time.Second), latency: time.Millisecond},
+		{timestamp: base, latency: 3 * time.Second},
+	}
+
+	report := buildReport(samples, 3*time.Second)
+
+	if len(report.ThroughputPerSecond) == 0 {
+		t.Fatalf("expected non-empty throughput series")
+	}
+	if report.ThroughputPerSecond[0] != 1 {
+		t.Errorf("expected 1 request bucketed at second 0 (the early starter), got %v", report.ThroughputPerSecond)
+	}
+	if len(report.ThroughputPerSecond) < 3 || report.ThroughputPerSecond[2] != 2 {
+		t.Errorf("expected 2 requests bucketed at second 2, got %v", report.ThroughputPerSecond)
+	}
+}
@@ -3,49 +3,79 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"sync"
 	"time"
 )
 
-// Client represents a TCP client connection to the cache server
+// Client represents a connection to a cache backend, speaking whichever
+// Protocol it was constructed with.
 type Client struct {
 	conn   net.Conn
 	reader *bufio.Reader
 	id     int
+	proto  Protocol
+
+	mu       sync.Mutex
+	cache    map[string]*cacheEntry
+	inflight map[string]*call
+	lastErr  error
 }
 
-// NewClient creates a new client connection to the server
-func NewClient(ctx context.Context, id int, serverAddr string) (*Client, error) {
+// NewClient creates a new client connection to the server, speaking the
+// given protocol.
+func NewClient(ctx context.Context, id int, serverAddr string, kind ProtocolKind) (*Client, error) {
 	var d net.Dialer
 	conn, err := d.DialContext(ctx, "tcp", serverAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect client %d: %w", id, err)
 	}
 
+	reader := bufio.NewReader(conn)
+	proto, err := newProtocol(kind, conn, reader)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set up protocol for client %d: %w", id, err)
+	}
+
 	return &Client{
-		conn:   conn,
-		reader: bufio.NewReader(conn),
-		id:     id,
+		conn:     conn,
+		reader:   reader,
+		id:       id,
+		proto:    proto,
+		cache:    make(map[string]*cacheEntry),
+		inflight: make(map[string]*call),
 	}, nil
 }
 
-// SendRequest sends a request to the server and returns the response
+// SendRequest fetches key from the server and returns its value, using
+// the client's configured Protocol.
 func (c *Client) SendRequest(ctx context.Context, key string) (string, error) {
-	// Send request
-	_, err := fmt.Fprintf(c.conn, "%s\n", key)
+	value, found, err := c.proto.Get(ctx, key)
 	if err != nil {
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
 		return "", fmt.Errorf("client %d failed to send request: %w", c.id, err)
 	}
-
-	// Read response
-	response, err := c.reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("client %d failed to read response: %w", c.id, err)
+	if !found {
+		return "", fmt.Errorf("client %d: key %q not found", c.id, key)
 	}
 
-	return response, nil
+	return value, nil
+}
+
+// Healthy reports whether the client's connection is still usable, i.e.
+// it has not seen a read/write error. Pool uses this to decide whether a
+// returned connection can be reused.
+func (c *Client) Healthy() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr == nil
 }
 
 // Close closes the client connection
@@ -53,14 +83,31 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
-// CacheStampedeDemo demonstrates cache stampede with concurrent requests
-func CacheStampedeDemo(ctx context.Context, serverAddr string, numClients int, targetKey string) {
+// CacheStampedeDemo demonstrates cache stampede with concurrent requests,
+// acquiring connections from pool instead of dialing fresh ones, so
+// measured latencies reflect server-side stampede behavior rather than
+// TCP handshake cost. When coalesce is true, every goroutine shares a
+// single pooled Client and issues requests through SendRequestCoalesced,
+// so only one of them actually hits the network per key; when false, each
+// goroutine acquires (and returns) its own connection from the pool.
+func CacheStampedeDemo(ctx context.Context, pool *Pool, numClients int, targetKey string, coalesce bool) {
 	fmt.Printf("🏃‍♂️ Starting Cache Stampede Demo\n")
 	fmt.Printf("   📊 Clients: %d\n", numClients)
 	fmt.Printf("   🎯 Target Key: %s\n", targetKey)
-	fmt.Printf("   🌐 Server: %s\n", serverAddr)
+	fmt.Printf("   🧩 Coalescing: %v\n", coalesce)
 	fmt.Println("=" + fmt.Sprintf("%60s", "="))
 
+	var shared *Client
+	if coalesce {
+		var err error
+		shared, err = pool.Get(ctx)
+		if err != nil {
+			fmt.Printf("❌ Shared client connection failed: %v\n", err)
+			return
+		}
+		defer pool.Put(shared)
+	}
+
 	var wg sync.WaitGroup
 	results := make(chan string, numClients)
 
@@ -70,19 +117,30 @@ func CacheStampedeDemo(ctx context.Context, serverAddr string, numClients int, t
 		go func(clientID int) {
 			defer wg.Done()
 
-			// Create client connection
-			client, err := NewClient(ctx, clientID, serverAddr)
-			if err != nil {
-				fmt.Printf("❌ Client %d connection failed: %v\n", clientID, err)
-				return
+			client := shared
+			if client == nil {
+				// Acquire a connection from the pool
+				var err error
+				client, err = pool.Get(ctx)
+				if err != nil {
+					fmt.Printf("❌ Client %d connection failed: %v\n", clientID, err)
+					return
+				}
+				defer pool.Put(client)
 			}
-			defer client.Close()
 
 			fmt.Printf("🔗 Client %d connected\n", clientID)
 
-			// Send request for the same key (this will cause cache stampede)
+			// Send request for the same key (this will cause cache stampede,
+			// unless coalescing collapses it into a single network round-trip)
 			start := time.Now()
-			response, err := client.SendRequest(ctx, targetKey)
+			var response string
+			var err error
+			if coalesce {
+				response, err = client.SendRequestCoalesced(ctx, targetKey)
+			} else {
+				response, err = client.SendRequest(ctx, targetKey)
+			}
 			duration := time.Since(start)
 
 			if err != nil {
@@ -114,8 +172,8 @@ func CacheStampedeDemo(ctx context.Context, serverAddr string, numClients int, t
 }
 
 // WarmUpCache sends a request to warm up the cache before the stampede
-func WarmUpCache(ctx context.Context, serverAddr string, key string) error {
-	client, err := NewClient(ctx, 0, serverAddr)
+func WarmUpCache(ctx context.Context, serverAddr string, key string, kind ProtocolKind) error {
+	client, err := NewClient(ctx, 0, serverAddr, kind)
 	if err != nil {
 		return fmt.Errorf("failed to create warmup client: %w", err)
 	}
@@ -135,6 +193,38 @@ func main() {
 	ctx := context.Background()
 	serverAddr := "localhost:8000"
 
+	protocolName := flag.String("protocol", "line", "backend protocol to speak: line, memcached, or redis")
+	loadgenMode := flag.Bool("loadgen", false, "drive load with LoadGen instead of running the fixed demos")
+	clients := flag.Int("clients", 10, "loadgen: number of persistent worker connections")
+	duration := flag.Duration("duration", 10*time.Second, "loadgen: how long to drive load for")
+	rps := flag.Int("rps", 0, "loadgen: target requests/sec (0 = closed-loop, workers run flat out)")
+	keys := flag.Int("keys", 100, "loadgen: size of the key space")
+	zipfS := flag.Float64("zipf-s", 0, "loadgen: Zipf skew (>1 enables hot-key distribution; <=1 is uniform)")
+	warmup := flag.Duration("warmup", 0, "loadgen: warmup period excluded from the report")
+	jsonOut := flag.String("json", "", "loadgen: file to write the JSON report to")
+	csvOut := flag.String("csv", "", "loadgen: file to write raw latency samples to as CSV")
+	flag.Parse()
+
+	kind, err := ParseProtocolKind(*protocolName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	if *loadgenMode {
+		runLoadGenCLI(ctx, LoadGenConfig{
+			ServerAddr: serverAddr,
+			Protocol:   kind,
+			Clients:    *clients,
+			Duration:   *duration,
+			RPS:        *rps,
+			Keys:       *keys,
+			ZipfS:      *zipfS,
+			Warmup:     *warmup,
+		}, *jsonOut, *csvOut)
+		return
+	}
+
 	fmt.Println("🚀 Cache Stampede Reproduction Tool")
 	fmt.Println("This Go client will send concurrent requests to demonstrate cache stampede")
 	fmt.Println()
@@ -145,7 +235,7 @@ func main() {
 
 	// Test connection to server
 	fmt.Println("🔍 Testing connection to server...")
-	testClient, err := NewClient(ctx, -1, serverAddr)
+	testClient, err := NewClient(ctx, -1, serverAddr, kind)
 	if err != nil {
 		fmt.Printf("❌ Cannot connect to server at %s: %v\n", serverAddr, err)
 		fmt.Println("💡 Make sure to run the Python server first: python3 server.py")
@@ -155,29 +245,87 @@ func main() {
 	fmt.Println("✅ Server connection successful!")
 	fmt.Println()
 
+	pool := NewPool(serverAddr, kind, 10, 20, 30*time.Second)
+	defer pool.Close()
+
 	// Demo 1: Cache Stampede - multiple requests for uncached key
 	fmt.Println("🎯 DEMO 1: Cache Stampede (cold cache)")
-	CacheStampedeDemo(ctx, serverAddr, 5, "stampede_key")
+	CacheStampedeDemo(ctx, pool, 5, "stampede_key", false)
 
 	fmt.Println()
 	time.Sleep(1 * time.Second)
 
 	// Demo 2: Cache Hit - requests for already cached key
 	fmt.Println("🎯 DEMO 2: Cache Hits (warm cache)")
-	CacheStampedeDemo(ctx, serverAddr, 5, "stampede_key")
+	CacheStampedeDemo(ctx, pool, 5, "stampede_key", false)
 
 	fmt.Println()
 	time.Sleep(1 * time.Second)
 
 	// Demo 3: Another stampede with different key
 	fmt.Println("🎯 DEMO 3: Another Cache Stampede (different key)")
-	CacheStampedeDemo(ctx, serverAddr, 8, "another_stampede_key")
+	CacheStampedeDemo(ctx, pool, 8, "another_stampede_key", false)
+
+	fmt.Println()
+	time.Sleep(1 * time.Second)
+
+	// Demo 3b: Same stampede, but with singleflight coalescing enabled
+	fmt.Println("🎯 DEMO 3b: Coalesced Cache Stampede (same key, shared client)")
+	CacheStampedeDemo(ctx, pool, 8, "another_stampede_key", true)
+
+	fmt.Println()
+	time.Sleep(1 * time.Second)
+
+	// Demo 4: XFetch - same herd, but mitigated via early probabilistic refresh
+	fmt.Println("🎯 DEMO 4: XFetch Stampede Mitigation")
+	if err := XFetchStampedeDemo(ctx, serverAddr, 5, "xfetch_key", kind, 2*time.Second, 3); err != nil {
+		fmt.Printf("❌ XFetch demo failed: %v\n", err)
+	}
 
 	fmt.Println()
 	fmt.Println("🎉 All demos completed!")
 	fmt.Println("💡 Check the Python server output to see the cache stampede happening!")
 }
 
+// runLoadGenCLI runs a LoadGen pass and reports the results, optionally
+// dumping the report as JSON and the raw samples as CSV.
+func runLoadGenCLI(ctx context.Context, cfg LoadGenConfig, jsonPath, csvPath string) {
+	fmt.Printf("📈 Starting LoadGen: %d clients, %v duration, %d rps (0=closed-loop), %d keys, zipf-s=%.2f\n",
+		cfg.Clients, cfg.Duration, cfg.RPS, cfg.Keys, cfg.ZipfS)
+
+	report, err := RunLoadGen(ctx, cfg)
+	if err != nil {
+		fmt.Printf("❌ LoadGen failed: %v\n", err)
+		return
+	}
+	report.Print()
+
+	if jsonPath != "" {
+		if err := writeReportFile(jsonPath, report.WriteJSON); err != nil {
+			fmt.Printf("❌ Failed to write JSON report to %s: %v\n", jsonPath, err)
+		} else {
+			fmt.Printf("📝 JSON report written to %s\n", jsonPath)
+		}
+	}
+	if csvPath != "" {
+		if err := writeReportFile(csvPath, report.WriteCSV); err != nil {
+			fmt.Printf("❌ Failed to write CSV samples to %s: %v\n", csvPath, err)
+		} else {
+			fmt.Printf("📝 CSV samples written to %s\n", csvPath)
+		}
+	}
+}
+
+// writeReportFile creates path and hands it to write, closing it afterwards.
+func writeReportFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f)
+}
+
 // min returns the smaller of two integers
 func min(a, b int) int {
 	if a < b {
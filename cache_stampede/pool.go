@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// idleClient tracks how long a pooled Client has been sitting idle, so
+// Pool can reap connections that have been unused for too long.
+type idleClient struct {
+	client    *Client
+	idleSince time.Time
+}
+
+// Pool manages a bounded set of reusable Client connections to a single
+// backend, so callers avoid paying TCP handshake cost (and masking it in
+// their timings) on every request.
+type Pool struct {
+	serverAddr  string
+	protocol    ProtocolKind
+	maxIdle     int
+	maxOpen     int
+	idleTimeout time.Duration
+
+	mu     sync.Mutex
+	idle   []*idleClient
+	open   int
+	nextID int
+}
+
+// NewPool creates a Pool that dials serverAddr using the given protocol,
+// keeping at most maxIdle idle connections and maxOpen connections open in
+// total (maxOpen <= 0 means unbounded). Idle connections unused for longer
+// than idleTimeout are closed and not returned by Get (idleTimeout <= 0
+// disables reaping).
+func NewPool(serverAddr string, protocol ProtocolKind, maxIdle, maxOpen int, idleTimeout time.Duration) *Pool {
+	return &Pool{
+		serverAddr:  serverAddr,
+		protocol:    protocol,
+		maxIdle:     maxIdle,
+		maxOpen:     maxOpen,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Get returns a healthy idle Client if one is available, discarding any
+// idle connections that have timed out or gone unhealthy along the way,
+// otherwise dials a new one as long as the pool is under its max-open cap.
+func (p *Pool) Get(ctx context.Context) (*Client, error) {
+	p.mu.Lock()
+	for len(p.idle) > 0 {
+		ic := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+
+		expired := p.idleTimeout > 0 && time.Since(ic.idleSince) > p.idleTimeout
+		if expired || !ic.client.Healthy() {
+			p.open--
+			ic.client.Close()
+			continue
+		}
+
+		p.mu.Unlock()
+		return ic.client, nil
+	}
+
+	if p.maxOpen > 0 && p.open >= p.maxOpen {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("pool: max open connections (%d) reached", p.maxOpen)
+	}
+	p.nextID++
+	id := p.nextID
+	p.open++
+	p.mu.Unlock()
+
+	client, err := NewClient(ctx, id, p.serverAddr, p.protocol)
+	if err != nil {
+		p.mu.Lock()
+		p.open--
+		p.mu.Unlock()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Put returns client to the pool for reuse. Unhealthy connections, or
+// connections beyond the max-idle cap, are closed instead.
+func (p *Pool) Put(client *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !client.Healthy() || len(p.idle) >= p.maxIdle {
+		p.open--
+		client.Close()
+		return
+	}
+	p.idle = append(p.idle, &idleClient{client: client, idleSince: time.Now()})
+}
+
+// Close closes every idle connection currently held by the pool.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var firstErr error
+	for _, ic := range p.idle {
+		if err := ic.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.idle = nil
+	p.open = 0
+	return firstErr
+}
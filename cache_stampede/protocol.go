@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol abstracts the wire format a Client speaks to its backend, so
+// the stampede machinery built on top of Client (XFetch, coalescing) can
+// be exercised against real caches and not just the bespoke Python demo
+// server.
+type Protocol interface {
+	// Get fetches key, reporting whether it was found in the cache.
+	Get(ctx context.Context, key string) (value string, found bool, err error)
+	// Set stores value under key with the given TTL.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+// ProtocolKind selects which Protocol implementation a Client speaks.
+type ProtocolKind int
+
+const (
+	// ProtocolLine is the bespoke "write key\n, read line\n" protocol used
+	// by the Python demo server in this repo.
+	ProtocolLine ProtocolKind = iota
+	// ProtocolMemcached is the memcached text protocol.
+	ProtocolMemcached
+	// ProtocolRedis is a minimal RESP (Redis) implementation.
+	ProtocolRedis
+)
+
+// ParseProtocolKind maps a CLI-friendly name ("line", "memcached", "redis")
+// to the ProtocolKind it selects.
+func ParseProtocolKind(name string) (ProtocolKind, error) {
+	switch name {
+	case "line":
+		return ProtocolLine, nil
+	case "memcached":
+		return ProtocolMemcached, nil
+	case "redis":
+		return ProtocolRedis, nil
+	default:
+		return 0, fmt.Errorf("unknown protocol %q (want line, memcached, or redis)", name)
+	}
+}
+
+// newProtocol builds the Protocol implementation for kind, sharing the
+// connection and buffered reader already set up for the Client.
+func newProtocol(kind ProtocolKind, conn net.Conn, reader *bufio.Reader) (Protocol, error) {
+	switch kind {
+	case ProtocolLine:
+		return &lineProtocol{conn: conn, reader: reader}, nil
+	case ProtocolMemcached:
+		return &memcachedProtocol{conn: conn, reader: reader}, nil
+	case ProtocolRedis:
+		return &redisProtocol{conn: conn, reader: reader}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol kind %d", kind)
+	}
+}
+
+// lineProtocol speaks the bespoke "write key\n, read line\n" protocol used
+// by the Python demo server in this repo.
+type lineProtocol struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (p *lineProtocol) Get(ctx context.Context, key string) (string, bool, error) {
+	if _, err := fmt.Fprintf(p.conn, "%s\n", key); err != nil {
+		return "", false, fmt.Errorf("line protocol: failed to send request: %w", err)
+	}
+	response, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", false, fmt.Errorf("line protocol: failed to read response: %w", err)
+	}
+	return response, true, nil
+}
+
+func (p *lineProtocol) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if _, err := fmt.Fprintf(p.conn, "SET %s %s\n", key, value); err != nil {
+		return fmt.Errorf("line protocol: failed to send set: %w", err)
+	}
+	if _, err := p.reader.ReadString('\n'); err != nil {
+		return fmt.Errorf("line protocol: failed to read set ack: %w", err)
+	}
+	return nil
+}
+
+// memcachedProtocol speaks the memcached text protocol: "get <key>\r\n" /
+// "VALUE <key> <flags> <bytes>\r\n<data>\r\nEND\r\n" for reads, and
+// "set <key> <flags> <exptime> <bytes>\r\n<data>\r\n" / "STORED\r\n" for
+// writes.
+type memcachedProtocol struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (p *memcachedProtocol) Get(ctx context.Context, key string) (string, bool, error) {
+	if _, err := fmt.Fprintf(p.conn, "get %s\r\n", key); err != nil {
+		return "", false, fmt.Errorf("memcached: failed to send get: %w", err)
+	}
+
+	header, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", false, fmt.Errorf("memcached: failed to read header: %w", err)
+	}
+	header = strings.TrimRight(header, "\r\n")
+	if header == "END" {
+		return "", false, nil
+	}
+
+	var key0 string
+	var flags, length int
+	if _, err := fmt.Sscanf(header, "VALUE %s %d %d", &key0, &flags, &length); err != nil {
+		return "", false, fmt.Errorf("memcached: malformed header %q: %w", header, err)
+	}
+	if length < 0 {
+		return "", false, fmt.Errorf("memcached: malformed header %q: negative length %d", header, length)
+	}
+
+	data := make([]byte, length+2) // +2 for the trailing "\r\n"
+	if _, err := io.ReadFull(p.reader, data); err != nil {
+		return "", false, fmt.Errorf("memcached: failed to read value: %w", err)
+	}
+
+	if end, err := p.reader.ReadString('\n'); err != nil || strings.TrimRight(end, "\r\n") != "END" {
+		return "", false, fmt.Errorf("memcached: expected END, got %q (err=%v)", end, err)
+	}
+
+	return string(data[:length]), true, nil
+}
+
+func (p *memcachedProtocol) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	exptime := int(ttl.Seconds())
+	if _, err := fmt.Fprintf(p.conn, "set %s 0 %d %d\r\n%s\r\n", key, exptime, len(value), value); err != nil {
+		return fmt.Errorf("memcached: failed to send set: %w", err)
+	}
+	reply, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("memcached: failed to read set reply: %w", err)
+	}
+	if strings.TrimRight(reply, "\r\n") != "STORED" {
+		return fmt.Errorf("memcached: set not stored, got %q", reply)
+	}
+	return nil
+}
+
+// redisProtocol speaks a minimal subset of RESP (the Redis Serialization
+// Protocol) sufficient for GET and SET: requests are encoded as RESP
+// arrays of bulk strings, e.g. "*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n".
+type redisProtocol struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (p *redisProtocol) Get(ctx context.Context, key string) (string, bool, error) {
+	if err := writeRESPCommand(p.conn, "GET", key); err != nil {
+		return "", false, fmt.Errorf("redis: failed to send GET: %w", err)
+	}
+	return readRESPBulkString(p.reader)
+}
+
+func (p *redisProtocol) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	seconds := strconv.Itoa(int(ttl.Seconds()))
+	if err := writeRESPCommand(p.conn, "SET", key, value, "EX", seconds); err != nil {
+		return fmt.Errorf("redis: failed to send SET: %w", err)
+	}
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("redis: failed to read SET reply: %w", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("redis: SET not OK, got %q", line)
+	}
+	return nil
+}
+
+// writeRESPCommand encodes args as a RESP array of bulk strings.
+func writeRESPCommand(w io.Writer, args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readRESPBulkString reads a RESP bulk string reply: "$-1\r\n" on a cache
+// miss, "$<len>\r\n<data>\r\n" otherwise.
+func readRESPBulkString(r *bufio.Reader) (string, bool, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", false, fmt.Errorf("redis: failed to read reply header: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "$") {
+		return "", false, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+	length, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return "", false, fmt.Errorf("redis: malformed length %q: %w", line, err)
+	}
+	if length < 0 {
+		return "", false, nil
+	}
+
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", false, fmt.Errorf("redis: failed to read value: %w", err)
+	}
+	return string(data[:length]), true, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestParseProtocolKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    ProtocolKind
+		wantErr bool
+	}{
+		{"line", ProtocolLine, false},
+		{"memcached", ProtocolMemcached, false},
+		{"redis", ProtocolRedis, false},
+		{"bogus", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProtocolKind(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProtocolKind(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseProtocolKind(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemcachedGetRejectsNegativeLength(t *testing.T) {
+	conn, srv := net.Pipe()
+	defer conn.Close()
+	defer srv.Close()
+
+	go func() {
+		r := bufio.NewReader(srv)
+		if _, err := r.ReadString('\n'); err != nil {
+			return
+		}
+		fmt.Fprintf(srv, "VALUE somekey 0 -5\r\n")
+	}()
+
+	p := &memcachedProtocol{conn: conn, reader: bufio.NewReader(conn)}
+	_, found, err := p.Get(context.Background(), "somekey")
+	if err == nil {
+		t.Fatalf("expected error for negative length header, got found=%v", found)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestXFetchExpiredWithR(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		name   string
+		entry  *cacheEntry
+		beta   float64
+		r      float64
+		expect bool
+	}{
+		{
+			name:   "well before expiry, r close to 1 (ln~0): not expired",
+			entry:  &cacheEntry{delta: time.Second, expiry: now.Add(10 * time.Second)},
+			beta:   1.0,
+			r:      0.999999,
+			expect: false,
+		},
+		{
+			name:   "at expiry: expired regardless of r",
+			entry:  &cacheEntry{delta: time.Second, expiry: now},
+			beta:   1.0,
+			r:      0.999999,
+			expect: true,
+		},
+		{
+			name:   "small r (large -ln(r)) triggers early recompute",
+			entry:  &cacheEntry{delta: 5 * time.Second, expiry: now.Add(10 * time.Second)},
+			beta:   1.0,
+			r:      0.0001, // -ln(r) ~= 9.2, * delta(5s) ~= 46s past now, well over the 10s expiry
+			expect: true,
+		},
+		{
+			name:   "beta 0 disables early recomputation entirely",
+			entry:  &cacheEntry{delta: 5 * time.Second, expiry: now.Add(10 * time.Second)},
+			beta:   0,
+			r:      0.0001,
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := xfetchExpiredWithR(now, tt.entry, tt.beta, tt.r)
+			if got != tt.expect {
+				t.Errorf("xfetchExpiredWithR(beta=%v, r=%v) = %v, want %v", tt.beta, tt.r, got, tt.expect)
+			}
+		})
+	}
+}